@@ -1,39 +1,227 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"embed"
+	"errors"
 	"fmt"
+	"html/template"
+	"io/fs"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
-func handler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println(r.URL.RawQuery)
-	fmt.Fprintf(w, `
-<!DOCTYPE html>
-<html>
-<head>
-<style>pre { font-family: monospace; font-size: 2em; white-space: pre; }</style>
-</head>
-<body>
-<pre>
-	><((((°>
-	              ><((((°>
+const (
+	version = "dev"
 
-		><((((°>
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 10 * time.Second
+	writeTimeout      = 10 * time.Second
+	idleTimeout       = 120 * time.Second
+	shutdownGrace     = 10 * time.Second
+)
+
+//go:embed assets/files
+var assetsFS embed.FS
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+// Page holds the data rendered into the greeter template.
+type Page struct {
+	Title    string
+	Message  string
+	Query    string
+	Hostname string
+	Version  string
+}
+
+func newHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "unknown"
+		}
+
+		page := Page{
+			Title:    "Hello Compose",
+			Message:  "Hello from a Compose Container!",
+			Query:    r.URL.RawQuery,
+			Hostname: hostname,
+			Version:  version,
+		}
+
+		if err := tmpl.ExecuteTemplate(w, "index.html", page); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// metrics tracks the counters exposed on /metrics in Prometheus text format.
+type metrics struct {
+	totalRequests    int64
+	inFlightRequests int64
+	statusCounts     sync.Map // int status code -> *int64 count
+}
+
+func (m *metrics) recordStatus(code int) {
+	counter, _ := m.statusCounts.LoadOrStore(code, new(int64))
+	atomic.AddInt64(counter.(*int64), 1)
+}
+
+func (m *metrics) handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		fmt.Fprintln(w, "# HELP hello_compose_requests_total Total number of HTTP requests received.")
+		fmt.Fprintln(w, "# TYPE hello_compose_requests_total counter")
+		fmt.Fprintf(w, "hello_compose_requests_total %d\n", atomic.LoadInt64(&m.totalRequests))
 
-  <°)))><	         ><((((°>
-              <°)))><
+		fmt.Fprintln(w, "# HELP hello_compose_requests_in_flight Number of HTTP requests currently being served.")
+		fmt.Fprintln(w, "# TYPE hello_compose_requests_in_flight gauge")
+		fmt.Fprintf(w, "hello_compose_requests_in_flight %d\n", atomic.LoadInt64(&m.inFlightRequests))
 
-        <°)))><         <')))))><
+		fmt.Fprintln(w, "# HELP hello_compose_requests_by_status_total Total number of HTTP requests by status code.")
+		fmt.Fprintln(w, "# TYPE hello_compose_requests_by_status_total counter")
+		m.statusCounts.Range(func(code, counter any) bool {
+			fmt.Fprintf(w, "hello_compose_requests_by_status_total{code=\"%d\"} %d\n", code.(int), atomic.LoadInt64(counter.(*int64)))
+			return true
+		})
+	}
+}
+
+// instrument wraps next with request counting and structured access logging.
+func (m *metrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		atomic.AddInt64(&m.inFlightRequests, 1)
+		defer atomic.AddInt64(&m.inFlightRequests, -1)
+		atomic.AddInt64(&m.totalRequests, 1)
+
+		reqID, err := newRequestID()
+		if err != nil {
+			reqID = "unknown"
+		}
 
-    Hello from a Compose Container!
-</pre>
-</body>
-</html>
-`)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		m.recordStatus(rec.status)
+		log.Printf("request_id=%s method=%s path=%s status=%d duration=%s",
+			reqID, r.Method, r.URL.Path, rec.status, time.Since(start))
+	})
+}
+
+// statusRecorder captures the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// newRequestID returns a random UUIDv4 for correlating a request's log lines.
+func newRequestID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ok")
+}
+
+// readyzHandler reports 200 once ready is true, and 503 beforehand or once
+// shutdown has begun.
+func readyzHandler(ready *atomic.Bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ok")
+	}
+}
+
+// listenAddr resolves the server's listen address from LISTEN_ADDR or PORT,
+// defaulting to ":8080" when neither is set.
+func listenAddr() string {
+	if addr := os.Getenv("LISTEN_ADDR"); addr != "" {
+		return addr
+	}
+	if port := os.Getenv("PORT"); port != "" {
+		return ":" + port
+	}
+	return ":8080"
 }
 
 func main() {
-	http.HandleFunc("/", handler)
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	static, err := fs.Sub(assetsFS, "assets/files")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tmpl, err := template.ParseFS(templatesFS, "templates/*.html")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var ready atomic.Bool
+	m := &metrics{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", newHandler(tmpl))
+	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(static))))
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler(&ready))
+	mux.HandleFunc("/metrics", m.handler())
+
+	srv := &http.Server{
+		Addr:              listenAddr(),
+		Handler:           m.instrument(mux),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		ready.Store(false)
+		log.Println("shutdown signal received, draining connections")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGrace)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("graceful shutdown failed: %v", err)
+		}
+	}()
+
+	ready.Store(true)
+	log.Printf("listening on %s", srv.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-shutdownDone
 }